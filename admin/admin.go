@@ -0,0 +1,239 @@
+/*
+	Copyright (C) 2024  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package admin implements rogueserver's operator-only HTTP API: Prometheus
+// metrics, health checks, and staff actions on accounts/savedata. It is
+// served on its own listener, bound to a config-controlled address and
+// never exposed through the public CORS policy.
+package admin
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/Greenlamp2/rogueserver/api/savedata"
+	"github.com/Greenlamp2/rogueserver/config"
+	"github.com/Greenlamp2/rogueserver/db"
+	"github.com/Greenlamp2/rogueserver/logging"
+	"github.com/Greenlamp2/rogueserver/metrics"
+)
+
+// activeAccountsWindow is the activity lookback used for the
+// active_accounts gauge.
+const activeAccountsWindow = 24 * time.Hour
+
+// NewServer builds the admin http.Server described by cfg. Every route
+// except /healthz and /readyz requires the configured bearer token. It also starts the
+// background loop that keeps the active_accounts gauge current.
+func NewServer(cfg config.AdminConfig) *http.Server {
+	mux := http.NewServeMux()
+
+	mux.Handle("/metrics", requireToken(cfg.Token, promhttp.Handler()))
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.HandleFunc("/readyz", handleReadyz)
+	mux.Handle("GET /admin/accounts/{uuid}/sessions", requireToken(cfg.Token, http.HandlerFunc(handleListSessions)))
+	mux.Handle("DELETE /admin/accounts/{uuid}/sessions", requireToken(cfg.Token, http.HandlerFunc(handleKickSessions)))
+	mux.Handle("DELETE /admin/savedata/{uuid}", requireToken(cfg.Token, http.HandlerFunc(handleDeleteSaveData)))
+	mux.Handle("POST /admin/savedata/{uuid}/restore", requireToken(cfg.Token, http.HandlerFunc(handleRestoreSaveData)))
+
+	go reportActiveAccounts()
+
+	return &http.Server{
+		Addr:    cfg.Addr,
+		Handler: mux,
+	}
+}
+
+// reportActiveAccounts refreshes the active_accounts gauge every minute for
+// the lifetime of the process.
+func reportActiveAccounts() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		count, err := db.ActiveAccountCount(activeAccountsWindow)
+		if err != nil {
+			logging.Log.Error().Err(err).Msg("failed to refresh active_accounts gauge")
+		} else {
+			metrics.ActiveAccounts.Set(float64(count))
+		}
+
+		<-ticker.C
+	}
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := r.Header.Get("Authorization")
+		if token == "" || !strings.HasPrefix(given, "Bearer ") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(given, "Bearer ")), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleReadyz pings the MySQL pool so load balancers stop routing to an
+// instance that has lost its database connection.
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if err := db.Ping(); err != nil {
+		http.Error(w, "database unreachable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func parseUUID(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	uuid, err := hex.DecodeString(r.PathValue("uuid"))
+	if err != nil {
+		http.Error(w, "invalid uuid", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return uuid, true
+}
+
+func handleListSessions(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := parseUUID(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := db.AccountSessions(uuid)
+	if err != nil {
+		logging.Log.Error().Bytes("uuid", uuid).Err(err).Msg("failed to fetch account sessions")
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, sessions)
+}
+
+func handleKickSessions(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := parseUUID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := db.ClearAccountSessions(uuid); err != nil {
+		logging.Log.Error().Bytes("uuid", uuid).Err(err).Msg("failed to kick account sessions")
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleDeleteSaveData(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := parseUUID(w, r)
+	if !ok {
+		return
+	}
+
+	datatype, slot, ok := parseDatatypeAndSlot(w, r)
+	if !ok {
+		return
+	}
+
+	if err := savedata.Delete(uuid, datatype, slot); err != nil {
+		logging.Log.Error().Bytes("uuid", uuid).Err(err).Msg("admin savedata delete failed")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleRestoreSaveData(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := parseUUID(w, r)
+	if !ok {
+		return
+	}
+
+	datatype, slot, ok := parseDatatypeAndSlot(w, r)
+	if !ok {
+		return
+	}
+
+	if err := savedata.Restore(uuid, datatype, slot); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "no soft-deleted savedata to restore", http.StatusNotFound)
+			return
+		}
+
+		logging.Log.Error().Bytes("uuid", uuid).Err(err).Msg("admin savedata restore failed")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// parseDatatypeAndSlot reads the datatype/slot query params used by the
+// delete/restore routes, writing a 400 and returning ok=false on anything
+// that isn't a recognized datatype (0 or 1, defaulting to 0 when absent)
+// or a valid integer slot (defaulting to 0 when absent). These endpoints
+// are destructive, so a typo must never silently fall back to a different
+// datatype/slot than the caller intended.
+func parseDatatypeAndSlot(w http.ResponseWriter, r *http.Request) (datatype, slot int, ok bool) {
+	if v := r.URL.Query().Get("datatype"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || (n != 0 && n != 1) {
+			http.Error(w, "invalid datatype", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		datatype = n
+	}
+
+	if v := r.URL.Query().Get("slot"); v != "" {
+		n, err := parseSlot(v)
+		if err != nil {
+			http.Error(w, "invalid slot", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		slot = n
+	}
+
+	return datatype, slot, true
+}
+
+func parseSlot(v string) (int, error) {
+	return strconv.Atoi(v)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.Log.Error().Err(err).Msg("failed to encode admin response")
+	}
+}
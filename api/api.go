@@ -0,0 +1,113 @@
+/*
+	Copyright (C) 2024  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package api registers rogueserver's public HTTP routes onto the mux
+// wrapped by the CORS/logging/metrics middleware in rogueserver.go.
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+	"strconv"
+
+	"github.com/Greenlamp2/rogueserver/api/savedata"
+	"github.com/Greenlamp2/rogueserver/logging"
+)
+
+// Init registers the public API routes on mux.
+func Init(mux *http.ServeMux) error {
+	mux.HandleFunc("POST /savedata/{uuid}/delete", handleSaveDataDelete)
+	mux.HandleFunc("POST /savedata/{uuid}/restore", handleSaveDataRestore)
+
+	return nil
+}
+
+func handleSaveDataDelete(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := parseUUID(w, r)
+	if !ok {
+		return
+	}
+
+	datatype, slot, ok := parseDatatypeAndSlot(w, r)
+	if !ok {
+		return
+	}
+
+	if err := savedata.Delete(uuid, datatype, slot); err != nil {
+		logging.Log.Error().Bytes("uuid", uuid).Err(err).Msg("savedata delete failed")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleSaveDataRestore(w http.ResponseWriter, r *http.Request) {
+	uuid, ok := parseUUID(w, r)
+	if !ok {
+		return
+	}
+
+	datatype, slot, ok := parseDatatypeAndSlot(w, r)
+	if !ok {
+		return
+	}
+
+	if err := savedata.Restore(uuid, datatype, slot); err != nil {
+		logging.Log.Error().Bytes("uuid", uuid).Err(err).Msg("savedata restore failed")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func parseUUID(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	uuid, err := hex.DecodeString(r.PathValue("uuid"))
+	if err != nil {
+		http.Error(w, "invalid uuid", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return uuid, true
+}
+
+// parseDatatypeAndSlot reads the datatype/slot query params used by the
+// delete/restore routes, writing a 400 and returning ok=false on anything
+// that isn't a recognized datatype (0 or 1, defaulting to 0 when absent)
+// or a valid integer slot (defaulting to 0 when absent).
+func parseDatatypeAndSlot(w http.ResponseWriter, r *http.Request) (datatype, slot int, ok bool) {
+	if v := r.URL.Query().Get("datatype"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || (n != 0 && n != 1) {
+			http.Error(w, "invalid datatype", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		datatype = n
+	}
+
+	if v := r.URL.Query().Get("slot"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "invalid slot", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		slot = n
+	}
+
+	return datatype, slot, true
+}
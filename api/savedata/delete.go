@@ -19,27 +19,43 @@ package savedata
 
 import (
 	"fmt"
-	"github.com/pagefaultgames/Greenlamp2/db"
-	"github.com/pagefaultgames/Greenlamp2/defs"
-	"log"
+
+	"github.com/Greenlamp2/rogueserver/db"
+	"github.com/Greenlamp2/rogueserver/defs"
+	"github.com/Greenlamp2/rogueserver/logging"
+	"github.com/Greenlamp2/rogueserver/metrics"
 )
 
-// /savedata/delete - delete save data
+// /savedata/delete - soft-delete save data. The underlying row is moved to
+// its deleted*SaveData table rather than dropped, so it can be brought back with
+// Restore within the configured retention window (see retention.go).
 func Delete(uuid []byte, datatype, slot int) error {
 	err := db.UpdateAccountLastActivity(uuid)
 	if err != nil {
-		log.Print("failed to update account last activity")
+		logging.Log.Error().Bytes("uuid", uuid).Err(err).Msg("failed to update account last activity")
 	}
 
 	switch datatype {
 	case 0: // System
-		return db.DeleteSystemSaveData(uuid)
+		err := db.DeleteSystemSaveData(uuid)
+		if err != nil {
+			logging.Log.Error().Bytes("uuid", uuid).Int("datatype", datatype).Err(err).Msg("delete failed")
+			return err
+		}
+		metrics.DeletesTotal.WithLabelValues("system").Inc()
+		return nil
 	case 1: // Session
 		if slot < 0 || slot >= defs.SessionSlotCount {
 			return fmt.Errorf("slot id %d out of range", slot)
 		}
 
-		return db.DeleteSessionSaveData(uuid, slot)
+		err := db.DeleteSessionSaveData(uuid, slot)
+		if err != nil {
+			logging.Log.Error().Bytes("uuid", uuid).Int("datatype", datatype).Int("slot", slot).Err(err).Msg("delete failed")
+			return err
+		}
+		metrics.DeletesTotal.WithLabelValues("session").Inc()
+		return nil
 	default:
 		return fmt.Errorf("invalid data type")
 	}
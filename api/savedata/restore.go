@@ -0,0 +1,52 @@
+/*
+	Copyright (C) 2024  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package savedata
+
+import (
+	"fmt"
+
+	"github.com/Greenlamp2/rogueserver/db"
+	"github.com/Greenlamp2/rogueserver/defs"
+	"github.com/Greenlamp2/rogueserver/logging"
+)
+
+// /savedata/restore - undo an accidental Delete within the retention window.
+// Once the daily retention purge has removed a row for good, Restore
+// returns sql.ErrNoRows.
+func Restore(uuid []byte, datatype, slot int) error {
+	switch datatype {
+	case 0: // System
+		err := db.RestoreSystemSaveData(uuid)
+		if err != nil {
+			logging.Log.Error().Bytes("uuid", uuid).Int("datatype", datatype).Err(err).Msg("restore failed")
+		}
+		return err
+	case 1: // Session
+		if slot < 0 || slot >= defs.SessionSlotCount {
+			return fmt.Errorf("slot id %d out of range", slot)
+		}
+
+		err := db.RestoreSessionSaveData(uuid, slot)
+		if err != nil {
+			logging.Log.Error().Bytes("uuid", uuid).Int("datatype", datatype).Int("slot", slot).Err(err).Msg("restore failed")
+		}
+		return err
+	default:
+		return fmt.Errorf("invalid data type")
+	}
+}
@@ -0,0 +1,49 @@
+/*
+	Copyright (C) 2024  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package savedata
+
+import (
+	"github.com/robfig/cron/v3"
+
+	"github.com/Greenlamp2/rogueserver/db"
+	"github.com/Greenlamp2/rogueserver/logging"
+)
+
+// StartRetentionPurge schedules a daily job that permanently removes
+// soft-deleted savedata older than retentionDays. It returns the running
+// *cron.Cron so the caller can Stop it on shutdown.
+func StartRetentionPurge(retentionDays int) *cron.Cron {
+	c := cron.New()
+
+	_, err := c.AddFunc("@daily", func() {
+		purged, err := db.PurgeExpiredSaveData(retentionDays)
+		if err != nil {
+			logging.Log.Error().Int("retentiondays", retentionDays).Err(err).Msg("savedata retention purge failed")
+			return
+		}
+
+		logging.Log.Info().Int("purged", purged).Int("retentiondays", retentionDays).Msg("savedata retention purge complete")
+	})
+	if err != nil {
+		logging.Log.Fatal().Err(err).Msg("failed to schedule savedata retention purge")
+	}
+
+	c.Start()
+
+	return c
+}
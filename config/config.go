@@ -0,0 +1,297 @@
+/*
+	Copyright (C) 2024  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package config loads rogueserver's config.yml: it applies defaults,
+// overlays environment variables for 12-factor deploys, and validates the
+// result before handing a single Config struct to the rest of the server.
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/Greenlamp2/rogueserver/logging"
+)
+
+// EnvPrefix is prepended to the dotted yaml field path to build the
+// environment variable name, e.g. server.acme.email -> ROGUESERVER_SERVER_ACME_EMAIL.
+const EnvPrefix = "ROGUESERVER"
+
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Logging  logging.Config `yaml:"logging"`
+	Admin    AdminConfig    `yaml:"admin"`
+	SaveData SaveDataConfig `yaml:"savedata"`
+}
+
+type ServerConfig struct {
+	Proto string     `yaml:"proto"`
+	Addr  string     `yaml:"addr"`
+	TLS   TLSConfig  `yaml:"tls"`
+	ACME  ACMEConfig `yaml:"acme"`
+
+	// ShutdownGraceSeconds bounds how long in-flight requests get to finish
+	// during a graceful shutdown before the listeners are forced closed.
+	ShutdownGraceSeconds int `yaml:"shutdowngraceseconds"`
+}
+
+type TLSConfig struct {
+	CertFile string `yaml:"cert"`
+	KeyFile  string `yaml:"key"`
+}
+
+type ACMEConfig struct {
+	Enabled  bool     `yaml:"enabled"`
+	Domains  []string `yaml:"domains"`
+	Email    string   `yaml:"email"`
+	CacheDir string   `yaml:"cachedir"`
+}
+
+type DatabaseConfig struct {
+	Username string `yaml:"user"`
+	Password string `yaml:"pass"`
+	Proto    string `yaml:"proto"`
+	Addr     string `yaml:"host"`
+	Database string `yaml:"database"`
+}
+
+// AdminConfig controls the operator-only admin listener (metrics, health
+// checks, account/savedata actions) that is never exposed via CORS.
+type AdminConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Addr    string `yaml:"addr"`
+	Token   string `yaml:"token"`
+}
+
+// SaveDataConfig controls how long soft-deleted savedata is kept before a
+// scheduled job purges it for good.
+type SaveDataConfig struct {
+	RetentionDays int `yaml:"retentiondays"`
+}
+
+func defaults() Config {
+	var cfg Config
+
+	cfg.Server.Proto = "tcp"
+	cfg.Server.Addr = "0.0.0.0:8080"
+	cfg.Server.ShutdownGraceSeconds = 15
+	cfg.Database.Proto = "tcp"
+	cfg.Logging.Level = "info"
+	cfg.Logging.Format = "console"
+	cfg.Admin.Addr = "127.0.0.1:9090"
+	cfg.SaveData.RetentionDays = 30
+
+	return cfg
+}
+
+// Load reads path (falling back to $ROGUESERVER_CONFIG, then "config.yml"),
+// overlays environment variables and validates the result. path may not
+// exist: defaults and the environment overlay alone can be enough to pass
+// validation for local/dev use.
+func Load(path string) (Config, error) {
+	cfg := defaults()
+
+	if path == "" {
+		path = os.Getenv(EnvPrefix + "_CONFIG")
+	}
+	if path == "" {
+		path = "config.yml"
+	}
+
+	f, err := os.Open(path)
+	if err == nil {
+		defer f.Close()
+
+		if err := yaml.NewDecoder(f).Decode(&cfg); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	overlayEnv(reflect.ValueOf(&cfg).Elem(), EnvPrefix)
+
+	if err := validate(cfg); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// overlayEnv walks v (a struct) recursively, setting each field from
+// $<prefix>_<FIELD> when present, using the field's yaml tag to build the
+// next path segment.
+func overlayEnv(v reflect.Value, prefix string) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		name := prefix + "_" + strings.ToUpper(tag)
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			overlayEnv(fv, name)
+			continue
+		}
+
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		switch fv.Kind() {
+		case reflect.String:
+			fv.SetString(raw)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err == nil {
+				fv.SetBool(b)
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(raw, 10, 64)
+			if err == nil {
+				fv.SetInt(n)
+			}
+		case reflect.Slice:
+			if fv.Type().Elem().Kind() == reflect.String {
+				fv.Set(reflect.ValueOf(strings.Split(raw, ",")))
+			}
+		}
+	}
+}
+
+// validate returns an error naming every required field that is missing or
+// contradictory, rather than stopping at the first one.
+func validate(cfg Config) error {
+	var problems []string
+
+	if cfg.Database.Username == "" {
+		problems = append(problems, "database.user is required")
+	}
+	if cfg.Database.Database == "" {
+		problems = append(problems, "database.database is required")
+	}
+	if cfg.Database.Addr == "" {
+		problems = append(problems, "database.host is required")
+	}
+
+	if cfg.Server.ACME.Enabled {
+		if len(cfg.Server.ACME.Domains) == 0 {
+			problems = append(problems, "server.acme.domains is required when server.acme.enabled is true")
+		}
+		if cfg.Server.ACME.CacheDir == "" {
+			problems = append(problems, "server.acme.cachedir is required when server.acme.enabled is true")
+		}
+		if cfg.Server.TLS.CertFile != "" || cfg.Server.TLS.KeyFile != "" {
+			problems = append(problems, "server.tls is mutually exclusive with server.acme.enabled")
+		}
+	}
+
+	if cfg.Admin.Enabled && cfg.Admin.Token == "" {
+		problems = append(problems, "admin.token is required when admin.enabled is true")
+	}
+
+	if cfg.SaveData.RetentionDays < 0 {
+		problems = append(problems, "savedata.retentiondays must not be negative")
+	}
+
+	if cfg.Server.ShutdownGraceSeconds < 0 {
+		problems = append(problems, "server.shutdowngraceseconds must not be negative")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+
+	return nil
+}
+
+// WriteDefault writes a fully-commented default config.yml to path, failing
+// if a file already exists there.
+func WriteDefault(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(defaultConfigYAML)
+	return err
+}
+
+const defaultConfigYAML = `# rogueserver configuration.
+# Every field here may also be set via an environment variable named
+# ROGUESERVER_<SECTION>_<FIELD>, where FIELD is the yaml key below
+# upper-cased, e.g. database.pass becomes ROGUESERVER_DATABASE_PASS.
+
+server:
+  # address the public API listener binds to
+  addr: "0.0.0.0:8080"
+  # protocol for the public listener: tcp or unix
+  proto: "tcp"
+  # seconds in-flight requests get to finish during a graceful shutdown
+  shutdowngraceseconds: 15
+
+  tls:
+    # manual certificate/key pair; mutually exclusive with acme below
+    cert: ""
+    key: ""
+
+  acme:
+    # automatic Let's Encrypt certificates via ACME HTTP-01
+    enabled: false
+    domains: []
+    email: ""
+    cachedir: "acme-cache"
+
+database:
+  user: ""
+  pass: ""
+  proto: "tcp"
+  host: ""
+  database: ""
+
+logging:
+  # trace, debug, info, warn, error
+  level: "info"
+  # json or console
+  format: "console"
+  # path to a log file; empty logs to stderr
+  file: ""
+
+admin:
+  # enables the operator-only admin listener (metrics, health, account/savedata actions)
+  enabled: false
+  addr: "127.0.0.1:9090"
+  # bearer token required on every admin request
+  token: ""
+
+savedata:
+  # days a soft-deleted save is kept before it is purged for good
+  retentiondays: 30
+`
@@ -0,0 +1,172 @@
+/*
+	Copyright (C) 2024  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefaults(t *testing.T) {
+	cfg := defaults()
+
+	want := Config{}
+	want.Server.Proto = "tcp"
+	want.Server.Addr = "0.0.0.0:8080"
+	want.Server.ShutdownGraceSeconds = 15
+	want.Database.Proto = "tcp"
+	want.Logging.Level = "info"
+	want.Logging.Format = "console"
+	want.Admin.Addr = "127.0.0.1:9090"
+	want.SaveData.RetentionDays = 30
+
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("defaults() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestOverlayEnv(t *testing.T) {
+	t.Setenv("ROGUESERVER_DATABASE_USER", "rogue")
+	t.Setenv("ROGUESERVER_DATABASE_PASS", "hunter2")
+	t.Setenv("ROGUESERVER_SERVER_ACME_DOMAINS", "a.example.com,b.example.com")
+	t.Setenv("ROGUESERVER_SERVER_ACME_ENABLED", "true")
+	t.Setenv("ROGUESERVER_SERVER_SHUTDOWNGRACESECONDS", "45")
+
+	cfg := defaults()
+	overlayEnv(reflect.ValueOf(&cfg).Elem(), EnvPrefix)
+
+	if cfg.Database.Username != "rogue" {
+		t.Errorf("Database.Username = %q, want %q", cfg.Database.Username, "rogue")
+	}
+	if cfg.Database.Password != "hunter2" {
+		t.Errorf("Database.Password = %q, want %q", cfg.Database.Password, "hunter2")
+	}
+	if !cfg.Server.ACME.Enabled {
+		t.Error("Server.ACME.Enabled = false, want true")
+	}
+	if want := []string{"a.example.com", "b.example.com"}; !reflect.DeepEqual(cfg.Server.ACME.Domains, want) {
+		t.Errorf("Server.ACME.Domains = %v, want %v", cfg.Server.ACME.Domains, want)
+	}
+	if cfg.Server.ShutdownGraceSeconds != 45 {
+		t.Errorf("Server.ShutdownGraceSeconds = %d, want 45", cfg.Server.ShutdownGraceSeconds)
+	}
+}
+
+func TestOverlayEnvLeavesUnsetFieldsAlone(t *testing.T) {
+	cfg := defaults()
+	overlayEnv(reflect.ValueOf(&cfg).Elem(), EnvPrefix)
+
+	if !reflect.DeepEqual(cfg, defaults()) {
+		t.Errorf("overlayEnv with no env set changed the config: got %+v, want %+v", cfg, defaults())
+	}
+}
+
+func TestOverlayEnvIgnoresUnparsableValues(t *testing.T) {
+	t.Setenv("ROGUESERVER_SERVER_SHUTDOWNGRACESECONDS", "not-a-number")
+
+	cfg := defaults()
+	overlayEnv(reflect.ValueOf(&cfg).Elem(), EnvPrefix)
+
+	if cfg.Server.ShutdownGraceSeconds != 15 {
+		t.Errorf("Server.ShutdownGraceSeconds = %d, want default 15 to survive an unparsable override", cfg.Server.ShutdownGraceSeconds)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	validDB := func(cfg Config) Config {
+		cfg.Database.Username = "rogue"
+		cfg.Database.Database = "rogueserver"
+		cfg.Database.Addr = "db:3306"
+		return cfg
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			name:    "missing database fields",
+			cfg:     defaults(),
+			wantErr: true,
+		},
+		{
+			name:    "valid minimal config",
+			cfg:     validDB(defaults()),
+			wantErr: false,
+		},
+		{
+			name: "acme enabled without domains or cachedir",
+			cfg: func() Config {
+				cfg := validDB(defaults())
+				cfg.Server.ACME.Enabled = true
+				cfg.Server.ACME.CacheDir = ""
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "acme mutually exclusive with manual tls",
+			cfg: func() Config {
+				cfg := validDB(defaults())
+				cfg.Server.ACME.Enabled = true
+				cfg.Server.ACME.Domains = []string{"example.com"}
+				cfg.Server.ACME.CacheDir = "acme-cache"
+				cfg.Server.TLS.CertFile = "cert.pem"
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "admin enabled without token",
+			cfg: func() Config {
+				cfg := validDB(defaults())
+				cfg.Admin.Enabled = true
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "negative retention days",
+			cfg: func() Config {
+				cfg := validDB(defaults())
+				cfg.SaveData.RetentionDays = -1
+				return cfg
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "negative shutdown grace",
+			cfg: func() Config {
+				cfg := validDB(defaults())
+				cfg.Server.ShutdownGraceSeconds = -1
+				return cfg
+			}(),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
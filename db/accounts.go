@@ -0,0 +1,78 @@
+/*
+	Copyright (C) 2024  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package db
+
+import (
+	"time"
+
+	"github.com/Greenlamp2/rogueserver/metrics"
+)
+
+// Session is one stored login session for an account, as surfaced by the
+// admin listener's /admin/accounts/{uuid}/sessions.
+type Session struct {
+	Token  string    `json:"token"`
+	Expire time.Time `json:"expire"`
+}
+
+// AccountSessions lists every stored session for uuid. Sessions whose
+// expire time has passed are not filtered out.
+func AccountSessions(uuid []byte) ([]Session, error) {
+	var sessions []Session
+
+	err := metrics.ObserveQuery("account_sessions", func() error {
+		rows, err := handle.Query("SELECT token, expire FROM sessions WHERE uuid = ?", uuid)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var s Session
+			if err := rows.Scan(&s.Token, &s.Expire); err != nil {
+				return err
+			}
+			sessions = append(sessions, s)
+		}
+
+		return rows.Err()
+	})
+
+	return sessions, err
+}
+
+// ClearAccountSessions kicks every active session for uuid, used by staff
+// to force a re-login.
+func ClearAccountSessions(uuid []byte) error {
+	return metrics.ObserveQuery("clear_account_sessions", func() error {
+		_, err := handle.Exec("DELETE FROM sessions WHERE uuid = ?", uuid)
+		return err
+	})
+}
+
+// ActiveAccountCount returns how many accounts have had activity within
+// the last window, feeding the active_accounts gauge.
+func ActiveAccountCount(window time.Duration) (int, error) {
+	var count int
+
+	err := metrics.ObserveQuery("active_account_count", func() error {
+		return handle.QueryRow("SELECT COUNT(*) FROM accounts WHERE lastActivity > ?", time.Now().Add(-window)).Scan(&count)
+	})
+
+	return count, err
+}
@@ -0,0 +1,69 @@
+/*
+	Copyright (C) 2024  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package db holds the MySQL connection pool and every query rogueserver
+// runs against it.
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/go-sql-driver/mysql"
+
+	"github.com/Greenlamp2/rogueserver/metrics"
+)
+
+var handle *sql.DB
+
+// Init opens the MySQL connection pool and verifies it is reachable.
+func Init(username, password, proto, addr, database string) error {
+	dsn := fmt.Sprintf("%s:%s@%s(%s)/%s?parseTime=true", username, password, proto, addr, database)
+
+	var err error
+	handle, err = sql.Open("mysql", dsn)
+	if err != nil {
+		return err
+	}
+
+	return handle.Ping()
+}
+
+// Close releases the connection pool. It is safe to call even if Init was
+// never called.
+func Close() error {
+	if handle == nil {
+		return nil
+	}
+
+	return handle.Close()
+}
+
+// Ping reports whether the connection pool can still reach MySQL, for use
+// by the admin listener's /readyz.
+func Ping() error {
+	return metrics.ObserveQuery("ping", handle.Ping)
+}
+
+// UpdateAccountLastActivity bumps the account's lastActivity timestamp,
+// used to drive the active-accounts metric.
+func UpdateAccountLastActivity(uuid []byte) error {
+	return metrics.ObserveQuery("update_account_last_activity", func() error {
+		_, err := handle.Exec("UPDATE accounts SET lastActivity = NOW() WHERE uuid = ?", uuid)
+		return err
+	})
+}
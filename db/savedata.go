@@ -0,0 +1,194 @@
+/*
+	Copyright (C) 2024  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Greenlamp2/rogueserver/metrics"
+)
+
+// DeleteSystemSaveData moves a system save to deletedSystemSaveData instead
+// of dropping it, so it can be brought back with RestoreSystemSaveData
+// within the retention window.
+func DeleteSystemSaveData(uuid []byte) error {
+	return metrics.ObserveQuery("delete_system_savedata", func() error {
+		tx, err := handle.Begin()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO deletedSystemSaveData (uuid, data, deletedAt)
+			SELECT uuid, data, NOW() FROM systemSaveData WHERE uuid = ?`, uuid)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM systemSaveData WHERE uuid = ?", uuid)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		return tx.Commit()
+	})
+}
+
+// DeleteSessionSaveData moves a session save to deletedSessionSaveData
+// instead of dropping it, so it can be brought back with
+// RestoreSessionSaveData within the retention window.
+func DeleteSessionSaveData(uuid []byte, slot int) error {
+	return metrics.ObserveQuery("delete_session_savedata", func() error {
+		tx, err := handle.Begin()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO deletedSessionSaveData (uuid, slot, data, deletedAt)
+			SELECT uuid, slot, data, NOW() FROM sessionSaveData WHERE uuid = ? AND slot = ?`, uuid, slot)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM sessionSaveData WHERE uuid = ? AND slot = ?", uuid, slot)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		return tx.Commit()
+	})
+}
+
+// RestoreSystemSaveData undoes a DeleteSystemSaveData within the retention
+// window. It returns sql.ErrNoRows if there is no matching soft-deleted
+// row, which covers both "never deleted" and "already purged".
+func RestoreSystemSaveData(uuid []byte) error {
+	return metrics.ObserveQuery("restore_system_savedata", func() error {
+		tx, err := handle.Begin()
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(`
+			INSERT INTO systemSaveData (uuid, data)
+			SELECT uuid, data FROM deletedSystemSaveData WHERE uuid = ?`, uuid)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if n == 0 {
+			tx.Rollback()
+			return sql.ErrNoRows
+		}
+
+		_, err = tx.Exec("DELETE FROM deletedSystemSaveData WHERE uuid = ?", uuid)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		return tx.Commit()
+	})
+}
+
+// RestoreSessionSaveData undoes a DeleteSessionSaveData within the
+// retention window. It returns sql.ErrNoRows if there is no matching
+// soft-deleted row, which covers both "never deleted" and "already
+// purged".
+func RestoreSessionSaveData(uuid []byte, slot int) error {
+	return metrics.ObserveQuery("restore_session_savedata", func() error {
+		tx, err := handle.Begin()
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(`
+			INSERT INTO sessionSaveData (uuid, slot, data)
+			SELECT uuid, slot, data FROM deletedSessionSaveData WHERE uuid = ? AND slot = ?`, uuid, slot)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if n == 0 {
+			tx.Rollback()
+			return sql.ErrNoRows
+		}
+
+		_, err = tx.Exec("DELETE FROM deletedSessionSaveData WHERE uuid = ? AND slot = ?", uuid, slot)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		return tx.Commit()
+	})
+}
+
+// PurgeExpiredSaveData permanently removes soft-deleted system and session
+// saves whose deletedAt is older than retentionDays, returning the number
+// of rows purged. It is run on a daily schedule by
+// savedata.StartRetentionPurge.
+func PurgeExpiredSaveData(retentionDays int) (int, error) {
+	var purged int64
+
+	err := metrics.ObserveQuery("purge_expired_savedata", func() error {
+		cutoff := time.Now().AddDate(0, 0, -retentionDays)
+
+		res, err := handle.Exec("DELETE FROM deletedSystemSaveData WHERE deletedAt < ?", cutoff)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		purged += n
+
+		res, err = handle.Exec("DELETE FROM deletedSessionSaveData WHERE deletedAt < ?", cutoff)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		purged += n
+
+		return nil
+	})
+
+	return int(purged), err
+}
@@ -0,0 +1,98 @@
+/*
+	Copyright (C) 2024  Pagefault Games
+
+	This program is free software: you can redistribute it and/or modify
+	it under the terms of the GNU Affero General Public License as published by
+	the Free Software Foundation, either version 3 of the License, or
+	(at your option) any later version.
+
+	This program is distributed in the hope that it will be useful,
+	but WITHOUT ANY WARRANTY; without even the implied warranty of
+	MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+	GNU Affero General Public License for more details.
+
+	You should have received a copy of the GNU Affero General Public License
+	along with this program.  If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package metrics holds the process-wide Prometheus collectors scraped by
+// the admin listener's /metrics endpoint.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rogueserver",
+		Name:      "http_requests_total",
+		Help:      "Public API requests by method, path and status.",
+	}, []string{"method", "path", "status"})
+
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "rogueserver",
+		Name:      "http_request_duration_seconds",
+		Help:      "Public API request latency by method and path.",
+	}, []string{"method", "path"})
+
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "rogueserver",
+		Name:      "db_query_duration_seconds",
+		Help:      "MySQL query latency by query name.",
+	}, []string{"query"})
+
+	ActiveAccounts = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "rogueserver",
+		Name:      "active_accounts",
+		Help:      "Accounts with activity in the last 24 hours.",
+	})
+
+	DeletesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rogueserver",
+		Name:      "savedata_deletes_total",
+		Help:      "Savedata delete calls by datatype.",
+	}, []string{"datatype"})
+)
+
+func init() {
+	prometheus.MustRegister(RequestsTotal, RequestDuration, DBQueryDuration, ActiveAccounts, DeletesTotal)
+}
+
+// Middleware records request rate and latency for every request handled by
+// next. It is applied to the public listener only; the admin listener is
+// scraped, not instrumented.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		RequestDuration.WithLabelValues(r.Method, r.URL.Path).Observe(time.Since(start).Seconds())
+		RequestsTotal.WithLabelValues(r.Method, r.URL.Path, strconv.Itoa(rec.status)).Inc()
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// ObserveQuery times fn and records it under the db_query_duration_seconds
+// histogram labeled with query, returning fn's error unchanged.
+func ObserveQuery(query string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	DBQueryDuration.WithLabelValues(query).Observe(time.Since(start).Seconds())
+	return err
+}
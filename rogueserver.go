@@ -18,110 +18,239 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/gob"
+	"errors"
 	"flag"
-	"log"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	"fmt"
-	"gopkg.in/yaml.v2"
 
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Greenlamp2/rogueserver/admin"
 	"github.com/Greenlamp2/rogueserver/api"
+	"github.com/Greenlamp2/rogueserver/api/savedata"
+	"github.com/Greenlamp2/rogueserver/config"
 	"github.com/Greenlamp2/rogueserver/db"
+	"github.com/Greenlamp2/rogueserver/logging"
+	"github.com/Greenlamp2/rogueserver/metrics"
 )
 
-type Config struct {
-    Server struct {
-        Host string `yaml:"host"`
-    } `yaml:"server"`
-    Database struct {
-        Username string `yaml:"user"`
-        Password string `yaml:"pass"`
-        Database string `yaml:"database"`
-        Host string `yaml:"host"`
-    } `yaml:"database"`
-}
-
-func processError(err error) {
-    if err != nil {
-        fmt.Println("Error:", err)
-        os.Exit(1) // Exiting the program with an error code
-    }
-}
-
-func readConfigFile() Config {
-    f, err := os.Open("config.yml")
-    if err != nil {
-        processError(err)
-    }
-    defer f.Close()
-
-    var cfg Config
-    decoder := yaml.NewDecoder(f)
-    err = decoder.Decode(&cfg)
-    if err != nil {
-        processError(err)
-    }
-    return cfg
-}
-
 func main() {
 	// flag stuff
 	debug := flag.Bool("debug", false, "use debug mode")
+	configPath := flag.String("config", "", "path to config.yml (defaults to $ROGUESERVER_CONFIG, then ./config.yml)")
+	initConfig := flag.Bool("init-config", false, "write a default config.yml to -config and exit")
 
-	var cfg = readConfigFile()
+	tlscert := flag.String("tlscert", "", "tls certificate path (overrides config.yml, mutually exclusive with server.acme)")
+	tlskey := flag.String("tlskey", "", "tls key path (overrides config.yml, mutually exclusive with server.acme)")
 
-	proto := flag.String("proto", "tcp", "protocol for api to use (tcp, unix)")
-	addr := flag.String("addr", "cfg.Server.Host", "network address for api to listen on")
-	tlscert := flag.String("tlscert", "", "tls certificate path")
-	tlskey := flag.String("tlskey", "", "tls key path")
+	flag.Parse()
 
-	dbuser := flag.String("dbuser", cfg.Database.Username, "database username")
-	dbpass := flag.String("dbpass", cfg.Database.Password, "database password")
-	dbproto := flag.String("dbproto", "tcp", "protocol for database connection")
-	dbaddr := flag.String("dbaddr", cfg.Database.Host, "database address")
-	dbname := flag.String("dbname", cfg.Database.Database, "database name")
+	if *initConfig {
+		path := *configPath
+		if path == "" {
+			path = os.Getenv(config.EnvPrefix + "_CONFIG")
+		}
+		if path == "" {
+			path = "config.yml"
+		}
 
-	flag.Parse()
+		if err := config.WriteDefault(path); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("wrote default config to", path)
+		return
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	if err := logging.Init(cfg.Logging); err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	if *tlscert == "" {
+		*tlscert = cfg.Server.TLS.CertFile
+	}
+	if *tlskey == "" {
+		*tlskey = cfg.Server.TLS.KeyFile
+	}
 
 	// register gob types
 	gob.Register([]interface{}{})
 	gob.Register(map[string]interface{}{})
 
 	// get database connection
-	err := db.Init(*dbuser, *dbpass, *dbproto, *dbaddr, *dbname)
+	err = db.Init(cfg.Database.Username, cfg.Database.Password, cfg.Database.Proto, cfg.Database.Addr, cfg.Database.Database)
 	if err != nil {
-		log.Fatalf("failed to initialize database: %s", err)
+		logging.Log.Fatal().Err(err).Msg("failed to initialize database")
 	}
 
+	// purge soft-deleted savedata past its retention window on a daily schedule
+	retentionCron := savedata.StartRetentionPurge(cfg.SaveData.RetentionDays)
+
 	// create listener
-	listener, err := createListener(*proto, *addr)
+	listener, err := createListener(cfg.Server.Proto, cfg.Server.Addr)
 	if err != nil {
-		log.Fatalf("failed to create net listener: %s", err)
+		logging.Log.Fatal().Err(err).Msg("failed to create net listener")
 	}
 
 	mux := http.NewServeMux()
 
 	// init api
 	if err := api.Init(mux); err != nil {
-		log.Fatal(err)
+		logging.Log.Fatal().Err(err).Msg("failed to initialize api")
 	}
 
 	// start web server
-	handler := prodHandler(mux)
+	handler := metrics.Middleware(logging.Middleware(prodHandler(mux)))
 	if *debug {
-		handler = debugHandler(mux)
+		handler = metrics.Middleware(logging.Middleware(debugHandler(mux)))
 	}
 
-	if *tlscert == "" {
-		err = http.Serve(listener, handler)
-	} else {
-		err = http.ServeTLS(listener, handler, *tlscert, *tlskey)
-	}
+	publicSrv, challengeSrv, err := newPublicServer(handler, cfg, *tlscert, *tlskey)
 	if err != nil {
-		log.Fatalf("failed to create http server or server errored: %s", err)
+		logging.Log.Fatal().Err(err).Msg("failed to configure public server")
+	}
+
+	var adminSrv *http.Server
+	if cfg.Admin.Enabled {
+		adminSrv = admin.NewServer(cfg.Admin)
 	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	eg, egCtx := errgroup.WithContext(ctx)
+
+	eg.Go(func() error {
+		var err error
+		if publicSrv.TLSConfig != nil {
+			err = publicSrv.ServeTLS(listener, "", "")
+		} else {
+			err = publicSrv.Serve(listener)
+		}
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	})
+
+	if adminSrv != nil {
+		eg.Go(func() error {
+			err := adminSrv.ListenAndServe()
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		})
+	}
+
+	if challengeSrv != nil {
+		eg.Go(func() error {
+			err := challengeSrv.ListenAndServe()
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		})
+	}
+
+	// either a SIGINT/SIGTERM or a sibling listener dying cancels egCtx,
+	// at which point we drain in-flight requests instead of slamming the process
+	eg.Go(func() error {
+		<-egCtx.Done()
+
+		grace := time.Duration(cfg.Server.ShutdownGraceSeconds) * time.Second
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+
+		if err := publicSrv.Shutdown(shutdownCtx); err != nil {
+			logging.Log.Error().Err(err).Msg("public server did not shut down cleanly")
+		}
+		if adminSrv != nil {
+			if err := adminSrv.Shutdown(shutdownCtx); err != nil {
+				logging.Log.Error().Err(err).Msg("admin server did not shut down cleanly")
+			}
+		}
+		if challengeSrv != nil {
+			if err := challengeSrv.Shutdown(shutdownCtx); err != nil {
+				logging.Log.Error().Err(err).Msg("acme challenge server did not shut down cleanly")
+			}
+		}
+
+		return nil
+	})
+
+	if err := eg.Wait(); err != nil {
+		logging.Log.Error().Err(err).Msg("server errored")
+	}
+
+	retentionCron.Stop()
+
+	if err := db.Close(); err != nil {
+		logging.Log.Error().Err(err).Msg("failed to close database pool")
+	}
+
+	if cfg.Server.Proto == "unix" {
+		os.Remove(cfg.Server.Addr)
+	}
+}
+
+// newPublicServer builds the public *http.Server, choosing between ACME,
+// manual TLS, and plain HTTP based on cfg and the (possibly config-sourced)
+// tlscert/tlskey flags. It does not start serving.
+//
+// When ACME is enabled it also returns the :80 HTTP-01 challenge server, so
+// the caller can add it to the same errgroup/shutdown path as the public
+// listener instead of leaving it as an unmanaged goroutine. challengeSrv is
+// nil whenever ACME is disabled.
+func newPublicServer(handler http.Handler, cfg config.Config, tlscert, tlskey string) (srv, challengeSrv *http.Server, err error) {
+	srv = &http.Server{Handler: handler}
+
+	if cfg.Server.ACME.Enabled {
+		if tlscert != "" || tlskey != "" {
+			return nil, nil, fmt.Errorf("-tlscert/-tlskey cannot be combined with server.acme in config.yml")
+		}
+
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.Server.ACME.Domains...),
+			Cache:      autocert.DirCache(cfg.Server.ACME.CacheDir),
+			Email:      cfg.Server.ACME.Email,
+		}
+
+		// serves HTTP-01 challenges on :80; other GET/HEAD requests are
+		// redirected to https and other methods get 400 Bad Request
+		challengeSrv = &http.Server{Addr: ":80", Handler: manager.HTTPHandler(nil)}
+
+		srv.TLSConfig = &tls.Config{GetCertificate: manager.GetCertificate}
+		return srv, challengeSrv, nil
+	}
+
+	if tlscert != "" {
+		cert, err := tls.LoadX509KeyPair(tlscert, tlskey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load tls cert/key: %w", err)
+		}
+		srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	return srv, nil, nil
 }
 
 func createListener(proto, addr string) (net.Listener, error) {